@@ -3,11 +3,14 @@ package logistics
 import (
     "context"
     "errors"
+    "flag"
     "fmt"
     "log"
     "math/rand"
+    "net/http"
     "os"
     "os/signal"
+    "runtime"
     "strconv"
     "sync"
     "syscall"
@@ -18,40 +21,82 @@ import (
     "github.com/coopnorge/interview-backend/internal/app/logistics/services/client"
     "github.com/coopnorge/interview-backend/internal/app/logistics/services/operator"
     "github.com/coopnorge/interview-backend/internal/app/pkg/printer"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
 )
 
 const (
-    appName    = "Coop Logistics Engine"
-    apiAddress = "127.0.0.1:50051" // TODO Improve later to use CMD ARGs
+    appName = "Coop Logistics Engine"
 
     maxWarehouses = 1<<8-1
     maxCargoUnits = 1<<10
+
+    // drainDeadline bounds how long in-flight deliveries get to finish on
+    // hardCtx once gracefulCtx has been canceled, before we force a hard stop.
+    drainDeadline = 30 * time.Second
+
+    // initialMoveWaitNumber seeds each worker's local move-wait interval; it
+    // used to be a single field on ServiceInstance mutated from every
+    // processDelivery call, which raced across workers.
+    initialMoveWaitNumber = 100
+)
+
+var (
+    apiAddrFlag        = flag.String("api-addr", "127.0.0.1:50051", "logistics API gRPC address")
+    connectTimeoutFlag = flag.Duration("connect-timeout", 30*time.Second, "timeout for the initial gRPC connection")
+    workerPoolSizeFlag = flag.Int("worker-pool-size", runtime.NumCPU()*4, "number of concurrent delivery workers (<=0 uses runtime.NumCPU()*4)")
+    metricsAddrFlag    = flag.String("metrics-addr", ":9090", "address to serve Prometheus metrics on")
 )
 
 // ServiceInstance of application
 type ServiceInstance struct {
-    ctx       context.Context
-    ctxCancel context.CancelFunc
+    // hardCtx is the parent context, canceled last - it backs in-flight
+    // logisticsClient calls so they may finish their current RPC during drain.
+    hardCtx       context.Context
+    hardCtxCancel context.CancelFunc
+
+    // gracefulCtx is a child of hardCtx, canceled first (on signal) - the
+    // dispatch loop watches it to stop handing out new deliveries.
+    gracefulCtx       context.Context
+    gracefulCtxCancel context.CancelFunc
 
     logisticsClient *client.APILogisticsClient
+    moveStream      *client.MoveStream
     worldOperator   *operator.WorldOperator
 
-    maxMoveWaitNumber int
-    reportTable       *printer.ASCIITablePrinter
-    statistics        *model.Statistics
+    metrics *Metrics
+
+    tracer         trace.Tracer
+    tracerShutdown func(context.Context) error
+
+    reportTable *printer.ASCIITablePrinter
+    statistics  *model.Statistics
 }
 
 // NewServiceInstance constructor
-func NewServiceInstance(lc *client.APILogisticsClient, wo *operator.WorldOperator) (*ServiceInstance, error) {
+func NewServiceInstance(
+    lc *client.APILogisticsClient,
+    wo *operator.WorldOperator,
+    tp trace.TracerProvider,
+    tracerShutdown func(context.Context) error,
+) (*ServiceInstance, error) {
     log.Printf("%s, initializing...\n", appName)
 
-    serviceCtx, serviceCtxCancel := context.WithCancel(context.Background())
-    connCtx, connCtxCancel := context.WithTimeout(serviceCtx, 30*time.Second)
+    hardCtx, hardCtxCancel := context.WithCancel(context.Background())
+    gracefulCtx, gracefulCtxCancel := context.WithCancel(hardCtx)
+
+    metrics := NewMetrics()
+    lc.SetMetrics(metrics)
+
+    apiAddress := *apiAddrFlag
+
+    connCtx, connCtxCancel := context.WithTimeout(hardCtx, *connectTimeoutFlag)
     defer connCtxCancel()
 
     log.Printf("%s, trying to connect to API - %s...\n", appName, apiAddress)
     if connErr := lc.Connect(apiAddress, connCtx); connErr != nil {
-        serviceCtxCancel()
+        gracefulCtxCancel()
+        hardCtxCancel()
         err := errors.New(fmt.Sprintf(
             "%s, failed to connect to API (%s), error: %v",
             appName,
@@ -62,15 +107,37 @@ func NewServiceInstance(lc *client.APILogisticsClient, wo *operator.WorldOperato
         return nil, err
     }
 
+    moveStream, streamErr := lc.OpenMoveStream(hardCtx)
+    if streamErr != nil {
+        gracefulCtxCancel()
+        hardCtxCancel()
+        err := errors.New(fmt.Sprintf(
+            "%s, failed to open MoveUnit stream (%s), error: %v",
+            appName,
+            apiAddress,
+            streamErr,
+        ))
+
+        return nil, err
+    }
+
     service := &ServiceInstance{
-        ctx:       serviceCtx,
-        ctxCancel: serviceCtxCancel,
+        hardCtx:       hardCtx,
+        hardCtxCancel: hardCtxCancel,
+
+        gracefulCtx:       gracefulCtx,
+        gracefulCtxCancel: gracefulCtxCancel,
 
         logisticsClient: lc,
+        moveStream:      moveStream,
         worldOperator:   wo,
 
-        maxMoveWaitNumber: 100,
-        reportTable:       printer.NewASCIITablePrinter(),
+        metrics: metrics,
+
+        tracer:         tp.Tracer(appName),
+        tracerShutdown: tracerShutdown,
+
+        reportTable: printer.NewASCIITablePrinter(),
         statistics: &model.Statistics{
             ExecTime: time.Now(),
             Operation: []*model.Operation{
@@ -80,7 +147,7 @@ func NewServiceInstance(lc *client.APILogisticsClient, wo *operator.WorldOperato
         },
     }
 
-    service.reportTable.AddHeader([]string{"Operation", "Count", "Errors"})
+    service.reportTable.AddHeader([]string{"Operation", "Count", "Errors", "RPC Attempts"})
     worldPopulationErr := wo.Populate(
         uint32(rand.Intn(maxWarehouses-10+1)+10),
         uint32(rand.Intn(maxCargoUnits-10+1)+10),
@@ -100,82 +167,174 @@ func (s *ServiceInstance) Run() error {
     go func() { // Handle graceful shutdown
         <-signals // Wait for the signal
 
-        log.Printf("%s, shutting down...\n", appName)
-
-        s.ctxCancel()
-        if s.logisticsClient != nil {
-            _ = s.logisticsClient.Disconnect()
-        }
+        log.Printf("%s, shutting down, no longer dispatching new deliveries...\n", appName)
 
-        log.Printf("%s, stopped!\n", appName)
+        s.gracefulCtxCancel()
+    }()
 
-        os.Exit(0)
+    metricsAddr := *metricsAddrFlag
+    metricsServer := newMetricsServer(metricsAddr, s.metrics.registry)
+    go func() {
+        log.Printf("%s, serving metrics on %s/metrics\n", appName, metricsAddr)
+        if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+            log.Printf("%s, metrics server error: %v\n", appName, err)
+        }
     }()
 
     deliveryUnits := s.worldOperator.GetDeliveryUnit()
     totalDeliveryUnits := len(deliveryUnits)
 
-    for {
-        var wg sync.WaitGroup
-        unitsReachedObjective := 0
+    // "delivery.run" is one root span for the whole worker-pool run, not one
+    // per tick - the worker pool (see deliveryWorker) retired the tick
+    // concept that an earlier version of this tracing spanned per-iteration,
+    // so every processDelivery step for every unit nests under this single
+    // span instead. That's an intentional consequence of the redesign, not
+    // drift: grouping by tick doesn't make sense once units are serviced by
+    // an unsynchronized pool of workers with no shared tick boundary.
+    runCtx, runSpan := s.tracer.Start(s.hardCtx, "delivery.run")
+    defer runSpan.End()
+
+    workerPoolSize := *workerPoolSizeFlag
+    if workerPoolSize <= 0 {
+        workerPoolSize = runtime.NumCPU() * 4
+    }
 
-        // Check if all units reached goal
-        for _, unit := range deliveryUnits {
-            if unit.Metadata == true {
-                unitsReachedObjective++
-            }
-        }
+    jobs := make(chan *model.GraphNode, totalDeliveryUnits)
+    for _, unit := range deliveryUnits {
+        jobs <- unit
+    }
 
-        if unitsReachedObjective == totalDeliveryUnits {
-            log.Println("All delivery units reached warehouse...")
-            break
-        }
+    // completion carries true for units that actually reached their
+    // warehouse and false for units abandoned mid-flight because
+    // gracefulCtx fired - the two must stay distinguishable so the
+    // reached-units gauge (and the final log line) don't overstate success.
+    completion := make(chan bool, totalDeliveryUnits)
+
+    var workers sync.WaitGroup
+    for i := 0; i < workerPoolSize; i++ {
+        workers.Add(1)
+        go s.deliveryWorker(runCtx, jobs, completion, &workers)
+    }
 
-        for _, unit := range deliveryUnits {
-            if unit.Metadata == true {
-                continue
+    s.metrics.SetUnitsRemaining(totalDeliveryUnits)
+
+    drained := make(chan struct{})
+    go func() {
+        reached := 0
+        for settled := 0; settled < totalDeliveryUnits; settled++ {
+            if <-completion {
+                reached++
+                s.metrics.SetUnitsReached(reached)
             }
 
-            wg.Add(1)
-            go s.processDelivery(unit, &wg)
+            s.metrics.SetUnitsRemaining(totalDeliveryUnits - settled - 1)
+        }
+
+        close(jobs)
+        workers.Wait()
+        close(drained)
+    }()
 
+    select {
+    case <-drained:
+        log.Println("All delivery units settled...")
+    case <-s.gracefulCtx.Done():
+        select {
+        case <-drained:
+        case <-time.After(drainDeadline):
+            log.Printf("%s, drain deadline exceeded, forcing remaining in-flight deliveries to stop...\n", appName)
         }
+    }
 
-        wg.Wait()
+    s.gracefulCtxCancel()
+    if s.moveStream != nil {
+        _ = s.moveStream.Close()
+    }
+    s.hardCtxCancel()
+    if s.logisticsClient != nil {
+        _ = s.logisticsClient.Disconnect()
     }
+    shutdownMetricsServer(metricsServer)
 
+    shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+    if err := s.tracerShutdown(shutdownCtx); err != nil {
+        log.Printf("%s, tracer provider shutdown error: %v\n", appName, err)
+    }
+    shutdownCancel()
+
+    attemptCounts := s.logisticsClient.AttemptCounts()
+    rpcSnapshot := s.metrics.Snapshot()
     for _, o := range s.statistics.Operation {
+        stat := rpcSnapshot[o.Name]
         s.reportTable.AddRow([]string{
             o.Name,
-            strconv.FormatUint(o.A, 10),
-            strconv.FormatUint(o.B, 10),
+            strconv.FormatUint(stat.OK, 10),
+            strconv.FormatUint(stat.Error, 10),
+            strconv.FormatUint(attemptCounts[o.Name], 10),
         })
     }
 
     fmt.Println("Execution time:", time.Since(s.statistics.ExecTime))
     fmt.Println(s.reportTable)
 
+    log.Printf("%s, stopped!\n", appName)
+
     return nil
 }
 
-func (s *ServiceInstance) processDelivery(unit *model.GraphNode, wg *sync.WaitGroup) {
+// deliveryWorker pulls units off jobs, performs one movement step on each,
+// and either re-queues the unit (still moving) or reports it on completion -
+// true if it reached its warehouse, false if gracefulCtx fired and it was
+// abandoned mid-flight instead. moveWait is worker-local so concurrent
+// workers never race over it.
+func (s *ServiceInstance) deliveryWorker(ctx context.Context, jobs chan *model.GraphNode, completion chan<- bool, wg *sync.WaitGroup) {
     defer wg.Done()
 
-    time.Sleep(time.Duration(s.maxMoveWaitNumber) * time.Microsecond)
-    s.maxMoveWaitNumber = rand.Intn(s.maxMoveWaitNumber+1) + 1
-    if s.maxMoveWaitNumber >= 1 {
-        s.maxMoveWaitNumber = s.maxMoveWaitNumber >> 1
+    moveWait := initialMoveWaitNumber
+
+    for unit := range jobs {
+        if s.processDelivery(ctx, unit, &moveWait) {
+            completion <- true
+            continue
+        }
+
+        select {
+        case <-s.gracefulCtx.Done():
+            completion <- false // abandoned mid-flight, stop dispatching it during shutdown
+        default:
+            jobs <- unit
+        }
+    }
+}
+
+// processDelivery performs a single movement step for unit and reports
+// whether it reached its warehouse.
+func (s *ServiceInstance) processDelivery(ctx context.Context, unit *model.GraphNode, moveWait *int) bool {
+    time.Sleep(time.Duration(*moveWait) * time.Microsecond)
+    *moveWait = rand.Intn(*moveWait+1) + 1
+    if *moveWait >= 1 {
+        *moveWait = *moveWait >> 1
     }
 
     oldCoordinate := *unit.Coordinate
     newCoordinate := s.worldOperator.MoveDeliveryUnitToNearestWarehouse(unit.ID)
     unitMessage := fmt.Sprintf("%s moving to - X:%d, Y:%d", unit.Name, newCoordinate.X, newCoordinate.Y)
 
+    deliveryCtx, deliverySpan := s.tracer.Start(ctx, "processDelivery", trace.WithAttributes(
+        attribute.Int64("unit.id", int64(unit.ID)),
+        attribute.String("unit.name", unit.Name),
+        attribute.String("coord.old", fmt.Sprintf("%d,%d", oldCoordinate.X, oldCoordinate.Y)),
+        attribute.String("coord.new", fmt.Sprintf("%d,%d", newCoordinate.X, newCoordinate.Y)),
+    ))
+    defer deliverySpan.End()
+
     log.Println(unitMessage)
 
-    s.statistics.Operation[0].AddA()
-    moveErr := s.logisticsClient.MoveUnit(
-        s.ctx,
+    // MoveStream.Send adds an event carrying its trace/span ID to
+    // deliveryCtx's own span - see its doc comment for why the delivery
+    // span can't be the parent of the shared client-streaming RPC's span.
+    moveErr := s.moveStream.Send(
+        deliveryCtx,
         &api.MoveUnitRequest{
             CargoUnitId: int64(unit.ID),
             Location: &api.Location{
@@ -186,23 +345,23 @@ func (s *ServiceInstance) processDelivery(unit *model.GraphNode, wg *sync.WaitGr
     )
     if moveErr != nil {
         log.Printf("filed to send MoveUnit %s, API error: %v\n", unitMessage, moveErr)
-        s.statistics.Operation[0].AddB()
 
-        return
+        return false
     } else if newCoordinate != oldCoordinate {
-        return
+        return false
     }
 
     announcement := fmt.Sprintf("%s - Reached Objective.", unitMessage)
     warehouse := s.worldOperator.FindEntityByCoordinate(newCoordinate, model.Warehouses)
     if warehouse == nil {
         log.Printf("Warehouses not found in coordinates X:%d Y:%d", newCoordinate.X, newCoordinate.Y)
-        return
+        return false
     }
 
-    s.statistics.Operation[1].AddA()
+    deliverySpan.SetAttributes(attribute.Int64("warehouse.id", int64(warehouse.ID)))
+
     reachErr := s.logisticsClient.UnitReachedWarehouse(
-        s.ctx,
+        deliveryCtx,
         &api.UnitReachedWarehouseRequest{
             Location: &api.Location{X: uint32(newCoordinate.X), Y: uint32(newCoordinate.Y)},
             Announcement: &api.WarehouseAnnouncement{
@@ -214,12 +373,11 @@ func (s *ServiceInstance) processDelivery(unit *model.GraphNode, wg *sync.WaitGr
     )
     if reachErr != nil {
         log.Printf("filed to send UnitReachedWarehouse %s, API error: %v\n", unitMessage, moveErr)
-        s.statistics.Operation[1].AddB()
-        return
+        return false
     }
 
     log.Println(announcement)
     unit.Metadata = true // Unit reached Warehouse
 
-    return
+    return true
 }
@@ -0,0 +1,133 @@
+package logistics
+
+import (
+    "context"
+    "log"
+    "net/http"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// RPCSnapshot summarizes a single operation's call counts for the
+// shutdown-time ASCII report.
+type RPCSnapshot struct {
+    OK    uint64
+    Error uint64
+}
+
+// Metrics owns the Prometheus registry for a ServiceInstance, fed by the
+// delivery pipeline and the gRPC client interceptor.
+type Metrics struct {
+    registry *prometheus.Registry
+
+    rpcCallsTotal  *prometheus.CounterVec
+    rpcDuration    *prometheus.HistogramVec
+    unitsRemaining prometheus.Gauge
+    unitsReached   prometheus.Gauge
+}
+
+// NewMetrics builds and registers the logistics collectors on a fresh registry.
+func NewMetrics() *Metrics {
+    m := &Metrics{
+        registry: prometheus.NewRegistry(),
+        rpcCallsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Name: "logistics_rpc_calls_total",
+            Help: "Total number of logistics RPC calls by operation and result.",
+        }, []string{"op", "result"}),
+        rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+            Name:    "logistics_rpc_duration_seconds",
+            Help:    "Duration of logistics RPC calls by operation.",
+            Buckets: prometheus.DefBuckets,
+        }, []string{"op"}),
+        unitsRemaining: prometheus.NewGauge(prometheus.GaugeOpts{
+            Name: "logistics_units_remaining",
+            Help: "Delivery units not yet settled, i.e. neither delivered to a warehouse nor abandoned at shutdown.",
+        }),
+        unitsReached: prometheus.NewGauge(prometheus.GaugeOpts{
+            Name: "logistics_units_reached",
+            Help: "Delivery units that have reached a warehouse.",
+        }),
+    }
+
+    m.registry.MustRegister(m.rpcCallsTotal, m.rpcDuration, m.unitsRemaining, m.unitsReached)
+
+    return m
+}
+
+// ObserveRPC implements client.MetricsRecorder.
+func (m *Metrics) ObserveRPC(op, result string, duration time.Duration) {
+    m.rpcCallsTotal.WithLabelValues(op, result).Inc()
+    m.rpcDuration.WithLabelValues(op).Observe(duration.Seconds())
+}
+
+// SetUnitsRemaining updates the logistics_units_remaining gauge.
+func (m *Metrics) SetUnitsRemaining(n int) {
+    m.unitsRemaining.Set(float64(n))
+}
+
+// SetUnitsReached updates the logistics_units_reached gauge.
+func (m *Metrics) SetUnitsReached(n int) {
+    m.unitsReached.Set(float64(n))
+}
+
+// Snapshot reads logistics_rpc_calls_total back out of the registry, grouped
+// by operation, for the shutdown-time ASCII report.
+func (m *Metrics) Snapshot() map[string]RPCSnapshot {
+    out := make(map[string]RPCSnapshot)
+
+    families, gatherErr := m.registry.Gather()
+    if gatherErr != nil {
+        log.Printf("failed to gather metrics snapshot: %v\n", gatherErr)
+        return out
+    }
+
+    for _, family := range families {
+        if family.GetName() != "logistics_rpc_calls_total" {
+            continue
+        }
+
+        for _, metric := range family.GetMetric() {
+            var op, result string
+            for _, label := range metric.GetLabel() {
+                switch label.GetName() {
+                case "op":
+                    op = label.GetValue()
+                case "result":
+                    result = label.GetValue()
+                }
+            }
+
+            snap := out[op]
+            switch result {
+            case "ok":
+                snap.OK = uint64(metric.GetCounter().GetValue())
+            case "error":
+                snap.Error = uint64(metric.GetCounter().GetValue())
+            }
+            out[op] = snap
+        }
+    }
+
+    return out
+}
+
+// newMetricsServer builds the HTTP server that exposes the registry on /metrics.
+func newMetricsServer(addr string, registry *prometheus.Registry) *http.Server {
+    mux := http.NewServeMux()
+    mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+    return &http.Server{Addr: addr, Handler: mux}
+}
+
+// shutdownMetricsServer gives the metrics server a bounded window to stop
+// serving before the rest of the shutdown sequence proceeds.
+func shutdownMetricsServer(server *http.Server) {
+    shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer shutdownCancel()
+
+    if err := server.Shutdown(shutdownCtx); err != nil {
+        log.Printf("%s, metrics server shutdown error: %v\n", appName, err)
+    }
+}
@@ -5,30 +5,47 @@ import (
 
     api "github.com/coopnorge/interview-backend/internal/app/logistics/api/v1"
     "github.com/google/wire"
+    "go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
     "google.golang.org/grpc"
-    "google.golang.org/grpc/credentials/insecure"
 )
 
 // ServiceSetForClient providers
-var ServiceSetForClient = wire.NewSet(NewLogisticsClient)
+var ServiceSetForClient = wire.NewSet(NewLogisticsClient, NewDefaultClientOptions, NewTransportConfigFromFlags)
 
 // APILogisticsClient to send requests about cargo unit movements
 type APILogisticsClient struct {
     api  api.CoopLogisticsEngineAPIClient
     conn *grpc.ClientConn
+
+    options   *ClientOptions
+    transport *TransportConfig
+    attempts  *attemptRecorder
+    metrics   MetricsRecorder
 }
 
 // NewLogisticsClient instance
-func NewLogisticsClient() *APILogisticsClient {
-    return &APILogisticsClient{}
+func NewLogisticsClient(options *ClientOptions, transport *TransportConfig) *APILogisticsClient {
+    return &APILogisticsClient{
+        options:   options,
+        transport: transport,
+        attempts:  newAttemptRecorder(),
+        metrics:   noopMetricsRecorder{},
+    }
 }
 
 // Connect to gRPC API
 func (lc *APILogisticsClient) Connect(serverAddr string, ctx context.Context) error {
+    creds, credsErr := lc.transport.credentials()
+    if credsErr != nil {
+        return credsErr
+    }
+
     conn, dialErr := grpc.DialContext(
         ctx,
         serverAddr,
-        grpc.WithTransportCredentials(insecure.NewCredentials()),
+        grpc.WithTransportCredentials(creds),
+        grpc.WithChainUnaryInterceptor(lc.attempts.interceptor(), lc.metricsInterceptor(), otelgrpc.UnaryClientInterceptor()),
+        grpc.WithChainStreamInterceptor(otelgrpc.StreamClientInterceptor()),
         grpc.WithBlock(),
     )
     if dialErr != nil {
@@ -46,22 +63,25 @@ func (lc *APILogisticsClient) Disconnect() error {
     return lc.conn.Close()
 }
 
+// AttemptCounts returns a snapshot of RPC attempts per operation, including
+// retries. The counts are per-operation totals: they do not tell hard
+// failures apart from calls that succeeded after retries.
+func (lc *APILogisticsClient) AttemptCounts() map[string]uint64 {
+    return lc.attempts.Counts()
+}
+
 // MoveUnit to new location
 func (lc *APILogisticsClient) MoveUnit(ctx context.Context, req *api.MoveUnitRequest) error {
-    _, moveRespErr := lc.api.MoveUnit(ctx, req)
-    if moveRespErr != nil {
+    return withRetry(ctx, lc.options, func(callCtx context.Context) error {
+        _, moveRespErr := lc.api.MoveUnit(callCtx, req)
         return moveRespErr
-    }
-
-    return nil
+    })
 }
 
 // UnitReachedWarehouse report that reach warehouse
 func (lc *APILogisticsClient) UnitReachedWarehouse(ctx context.Context, req *api.UnitReachedWarehouseRequest) error {
-    _, moveRespErr := lc.api.UnitReachedWarehouse(ctx, req)
-    if moveRespErr != nil {
+    return withRetry(ctx, lc.options, func(callCtx context.Context) error {
+        _, moveRespErr := lc.api.UnitReachedWarehouse(callCtx, req)
         return moveRespErr
-    }
-
-    return nil
+    })
 }
@@ -0,0 +1,49 @@
+package client
+
+import (
+    "context"
+    "strings"
+    "time"
+
+    "google.golang.org/grpc"
+)
+
+// MetricsRecorder receives per-RPC outcome/duration observations. Satisfied
+// by logistics.Metrics; kept as an interface here so this package does not
+// need to depend on Prometheus directly.
+type MetricsRecorder interface {
+    ObserveRPC(op, result string, duration time.Duration)
+}
+
+type noopMetricsRecorder struct{}
+
+func (noopMetricsRecorder) ObserveRPC(string, string, time.Duration) {}
+
+// SetMetrics attaches a MetricsRecorder that observes every unary RPC attempt
+// made through this client, and each MoveStream.Send once (however many
+// attempts it took). Must be called before Connect.
+func (lc *APILogisticsClient) SetMetrics(m MetricsRecorder) {
+    lc.metrics = m
+}
+
+// metricsInterceptor records call outcome and duration for every RPC attempt.
+func (lc *APILogisticsClient) metricsInterceptor() grpc.UnaryClientInterceptor {
+    return func(ctx context.Context, fullMethod string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+        op := fullMethod
+        if idx := strings.LastIndex(fullMethod, "/"); idx != -1 {
+            op = fullMethod[idx+1:]
+        }
+
+        start := time.Now()
+        err := invoker(ctx, fullMethod, req, reply, cc, opts...)
+
+        result := "ok"
+        if err != nil {
+            result = "error"
+        }
+
+        lc.metrics.ObserveRPC(op, result, time.Since(start))
+
+        return err
+    }
+}
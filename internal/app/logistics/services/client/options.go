@@ -0,0 +1,30 @@
+package client
+
+import "time"
+
+// ClientOptions configures retry behaviour and per-RPC deadlines applied by
+// APILogisticsClient around MoveUnit / UnitReachedWarehouse.
+type ClientOptions struct {
+    // MaxAttempts is the total number of tries per call, including the first.
+    MaxAttempts int
+
+    // InitialBackoff is the base delay before the first retry; it doubles on
+    // each subsequent attempt (capped at MaxBackoff) and gets jitter applied.
+    InitialBackoff time.Duration
+    MaxBackoff     time.Duration
+
+    // CallTimeout bounds a single attempt via context.WithTimeout, derived
+    // from the caller's context.
+    CallTimeout time.Duration
+}
+
+// NewDefaultClientOptions returns the defaults used when the caller does not
+// supply its own ClientOptions.
+func NewDefaultClientOptions() *ClientOptions {
+    return &ClientOptions{
+        MaxAttempts:    4,
+        InitialBackoff: 100 * time.Millisecond,
+        MaxBackoff:     2 * time.Second,
+        CallTimeout:    5 * time.Second,
+    }
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+    "context"
+    "math/rand"
+    "strings"
+    "sync"
+    "time"
+
+    "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+)
+
+// isRetryable reports whether err is worth retrying against the API.
+func isRetryable(err error) bool {
+    switch status.Code(err) {
+    case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+        return true
+    default:
+        return false
+    }
+}
+
+// backoff returns the delay, with full jitter, to wait after the given
+// (1-indexed) attempt has failed, i.e. before attempt+1.
+func backoff(attempt int, opts *ClientOptions) time.Duration {
+    d := opts.InitialBackoff << uint(attempt-1)
+    if d <= 0 || d > opts.MaxBackoff {
+        d = opts.MaxBackoff
+    }
+
+    return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// withRetry runs call, retrying on retryable errors for up to
+// opts.MaxAttempts attempts in total (the first one included), with
+// exponential backoff and jitter between attempts. Each attempt
+// gets its own deadline derived from ctx via opts.CallTimeout.
+func withRetry(ctx context.Context, opts *ClientOptions, call func(ctx context.Context) error) error {
+    var lastErr error
+
+    for attempt := 1; attempt <= opts.MaxAttempts; attempt++ {
+        callCtx, callCancel := context.WithTimeout(ctx, opts.CallTimeout)
+        lastErr = call(callCtx)
+        callCancel()
+
+        if lastErr == nil {
+            return nil
+        }
+
+        if attempt == opts.MaxAttempts || !isRetryable(lastErr) {
+            return lastErr
+        }
+
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(backoff(attempt, opts)):
+        }
+    }
+
+    return lastErr
+}
+
+// attemptRecorder tallies RPC attempts per operation name, retries included.
+// It keeps one running total per operation; how many attempts any single
+// call took is not recorded.
+type attemptRecorder struct {
+    mu       sync.Mutex
+    attempts map[string]uint64
+}
+
+func newAttemptRecorder() *attemptRecorder {
+    return &attemptRecorder{attempts: make(map[string]uint64)}
+}
+
+// interceptor is installed via grpc.WithChainUnaryInterceptor so every
+// attempt - including retries - is counted uniformly, regardless of which
+// method on APILogisticsClient triggered it.
+func (r *attemptRecorder) interceptor() grpc.UnaryClientInterceptor {
+    return func(ctx context.Context, fullMethod string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+        op := fullMethod
+        if idx := strings.LastIndex(fullMethod, "/"); idx != -1 {
+            op = fullMethod[idx+1:]
+        }
+
+        r.record(op)
+
+        return invoker(ctx, fullMethod, req, reply, cc, opts...)
+    }
+}
+
+// record tallies a single attempt for op. Exported to the package (not just
+// the unary interceptor above) so call sites with no unary interceptor to
+// hang off - MoveStream.Send, notably - can still be counted.
+func (r *attemptRecorder) record(op string) {
+    r.mu.Lock()
+    r.attempts[op]++
+    r.mu.Unlock()
+}
+
+// Counts returns a snapshot of attempts recorded per operation name.
+func (r *attemptRecorder) Counts() map[string]uint64 {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    snapshot := make(map[string]uint64, len(r.attempts))
+    for op, count := range r.attempts {
+        snapshot[op] = count
+    }
+
+    return snapshot
+}
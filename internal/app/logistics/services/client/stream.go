@@ -0,0 +1,188 @@
+package client
+
+import (
+    "context"
+    "log"
+    "sync"
+    "time"
+
+    api "github.com/coopnorge/interview-backend/internal/app/logistics/api/v1"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
+)
+
+// moveUnitStreamOp is the operation name MoveStream reports attempts and
+// metrics under, matching the unary MoveUnit method it replaces so the
+// shutdown report's "MoveUnit" row still reflects real traffic.
+const moveUnitStreamOp = "MoveUnit"
+
+// MoveStream is a long-lived, client-streaming MoveUnitStream wrapper: callers
+// push MoveUnitRequest messages onto it while a background goroutine drains
+// server acks, so delivery-step latency stops being dominated by per-call
+// gRPC round-trips.
+type MoveStream struct {
+    lc  *APILogisticsClient
+    ctx context.Context
+
+    mu     sync.Mutex
+    stream api.CoopLogisticsEngineAPI_MoveUnitStreamClient
+}
+
+// OpenMoveStream opens a new MoveStream against the API, starting the
+// background ack-draining goroutine. ctx governs the lifetime of the stream;
+// it is expected to outlive individual deliveries (typically the service's
+// hard shutdown context).
+func (lc *APILogisticsClient) OpenMoveStream(ctx context.Context) (*MoveStream, error) {
+    ms := &MoveStream{lc: lc, ctx: ctx}
+    if err := ms.reconnect(); err != nil {
+        return nil, err
+    }
+
+    return ms, nil
+}
+
+// reconnect dials a fresh MoveUnitStream, retrying with backoff per
+// ClientOptions. The stream is expected to live for the whole process, so a
+// transient dial failure must not leave it permanently dead - unlike a
+// single RPC attempt, there's no caller-supplied context to bound this
+// against besides ms.ctx itself.
+func (ms *MoveStream) reconnect() error {
+    opts := ms.lc.options
+    var lastErr error
+
+    for attempt := 1; attempt <= opts.MaxAttempts; attempt++ {
+        stream, err := ms.lc.api.MoveUnitStream(ms.ctx)
+        if err == nil {
+            ms.mu.Lock()
+            ms.stream = stream
+            ms.mu.Unlock()
+
+            go ms.drainAcks()
+
+            return nil
+        }
+
+        lastErr = err
+        if attempt == opts.MaxAttempts {
+            break
+        }
+
+        select {
+        case <-ms.ctx.Done():
+            return ms.ctx.Err()
+        case <-time.After(backoff(attempt, opts)):
+        }
+    }
+
+    return lastErr
+}
+
+// drainAcks keeps the stream's receive side moving so the server's flow
+// control doesn't stall sends, and reconnects if the stream errors mid-run.
+func (ms *MoveStream) drainAcks() {
+    ms.mu.Lock()
+    stream := ms.stream
+    ms.mu.Unlock()
+
+    for {
+        if _, recvErr := stream.Recv(); recvErr != nil {
+            if ms.ctx.Err() != nil {
+                return // shutting down, nothing to reconnect for
+            }
+
+            log.Printf("MoveStream ack stream errored, reconnecting: %v\n", recvErr)
+            if reconnErr := ms.reconnect(); reconnErr != nil {
+                log.Printf("MoveStream reconnect failed: %v\n", reconnErr)
+            }
+
+            return
+        }
+    }
+}
+
+// Send batches a MoveUnitRequest onto the stream, retrying on retryable
+// errors (reconnecting first) with the same MaxAttempts and backoff as the
+// unary RPCs. ClientOptions.CallTimeout is not applied: a single stream
+// send has no per-attempt deadline.
+//
+// ctx carries the caller's per-delivery span. gRPC fixes a client stream's
+// metadata at the MoveUnitStream(ctx) call in reconnect(), so an individual
+// Send can't propagate its own trace headers to the server - instead we
+// record the delivery span's trace/span ID as an event on the delivery span
+// itself. The MoveUnitStream RPC span (traced via
+// otelgrpc.StreamClientInterceptor on Connect) belongs to the trace of the
+// context the stream was opened with, so the event is not linked to it and
+// the delivery span does not nest under it.
+func (ms *MoveStream) Send(ctx context.Context, req *api.MoveUnitRequest) error {
+    span := trace.SpanFromContext(ctx)
+    sc := span.SpanContext()
+    if sc.IsValid() {
+        span.AddEvent("MoveStream.Send", trace.WithAttributes(
+            attribute.String("move_stream.trace_id", sc.TraceID().String()),
+            attribute.String("move_stream.span_id", sc.SpanID().String()),
+        ))
+    }
+
+    start := time.Now()
+    err := ms.sendWithRetry(ctx, req)
+
+    result := "ok"
+    if err != nil {
+        result = "error"
+        span.RecordError(err)
+    }
+    ms.lc.metrics.ObserveRPC(moveUnitStreamOp, result, time.Since(start))
+
+    return err
+}
+
+// sendWithRetry retries req up to opts.MaxAttempts times, reconnecting
+// before each retry since a failed send leaves the stream's state
+// unreliable. Since this RPC never goes through the unary interceptor
+// chain, attempts are recorded here explicitly rather than by an
+// interceptor.
+func (ms *MoveStream) sendWithRetry(ctx context.Context, req *api.MoveUnitRequest) error {
+    opts := ms.lc.options
+    var lastErr error
+
+    for attempt := 1; attempt <= opts.MaxAttempts; attempt++ {
+        ms.lc.attempts.record(moveUnitStreamOp)
+
+        ms.mu.Lock()
+        lastErr = ms.stream.Send(req)
+        ms.mu.Unlock()
+
+        if lastErr == nil {
+            return nil
+        }
+
+        if attempt == opts.MaxAttempts || !isRetryable(lastErr) {
+            return lastErr
+        }
+
+        if reconnErr := ms.reconnect(); reconnErr != nil {
+            log.Printf("MoveStream reconnect before retry failed: %v\n", reconnErr)
+            return lastErr
+        }
+
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(backoff(attempt, opts)):
+        }
+    }
+
+    return lastErr
+}
+
+// Close gracefully closes the send side of the stream.
+func (ms *MoveStream) Close() error {
+    ms.mu.Lock()
+    defer ms.mu.Unlock()
+
+    if ms.stream == nil {
+        return nil
+    }
+
+    return ms.stream.CloseSend()
+}
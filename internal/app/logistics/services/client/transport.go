@@ -0,0 +1,83 @@
+package client
+
+import (
+    "crypto/tls"
+    "crypto/x509"
+    "flag"
+    "fmt"
+    "log"
+    "os"
+
+    "google.golang.org/grpc/credentials"
+    "google.golang.org/grpc/credentials/insecure"
+)
+
+var (
+    tlsCAFile     = flag.String("tls-ca", "", "path to a CA bundle used to verify the API server certificate")
+    tlsCertFile   = flag.String("tls-cert", "", "path to a client certificate for mTLS")
+    tlsKeyFile    = flag.String("tls-key", "", "path to the client certificate's private key for mTLS")
+    tlsServerName = flag.String("tls-server-name", "", "override the server name used for TLS verification")
+    tlsSkipVerify = flag.Bool("tls-insecure-skip-verify", false, "skip API server certificate verification (dev only)")
+)
+
+// TransportConfig configures the transport credentials used by
+// APILogisticsClient.Connect - TLS once CAFile or CertFile is set, mTLS when
+// CertFile/KeyFile are provided. ServerName and InsecureSkipVerify only take
+// effect with TLS; without CAFile or CertFile the connection is insecure.
+type TransportConfig struct {
+    ServerName         string
+    CAFile             string
+    CertFile           string
+    KeyFile            string
+    InsecureSkipVerify bool
+}
+
+// NewTransportConfigFromFlags builds a TransportConfig from the --tls-* flags.
+func NewTransportConfigFromFlags() *TransportConfig {
+    return &TransportConfig{
+        ServerName:         *tlsServerName,
+        CAFile:             *tlsCAFile,
+        CertFile:           *tlsCertFile,
+        KeyFile:            *tlsKeyFile,
+        InsecureSkipVerify: *tlsSkipVerify,
+    }
+}
+
+// credentials builds transport credentials from TransportConfig, falling
+// back to insecure (with a warning) when no TLS material is supplied.
+func (tc *TransportConfig) credentials() (credentials.TransportCredentials, error) {
+    if tc.CAFile == "" && tc.CertFile == "" {
+        log.Println("no TLS material configured, falling back to insecure transport credentials")
+        return insecure.NewCredentials(), nil
+    }
+
+    tlsConfig := &tls.Config{
+        ServerName:         tc.ServerName,
+        InsecureSkipVerify: tc.InsecureSkipVerify,
+    }
+
+    if tc.CAFile != "" {
+        caCert, readErr := os.ReadFile(tc.CAFile)
+        if readErr != nil {
+            return nil, fmt.Errorf("reading CA bundle %q: %w", tc.CAFile, readErr)
+        }
+
+        pool := x509.NewCertPool()
+        if !pool.AppendCertsFromPEM(caCert) {
+            return nil, fmt.Errorf("no certificates found in CA bundle %q", tc.CAFile)
+        }
+
+        tlsConfig.RootCAs = pool
+    }
+
+    if tc.CertFile != "" {
+        cert, certErr := tls.LoadX509KeyPair(tc.CertFile, tc.KeyFile)
+        if certErr != nil {
+            return nil, fmt.Errorf("loading client certificate %q: %w", tc.CertFile, certErr)
+        }
+
+        tlsConfig.Certificates = []tls.Certificate{cert}
+    }
+
+    return credentials.NewTLS(tlsConfig), nil
+}
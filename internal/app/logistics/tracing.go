@@ -0,0 +1,59 @@
+package logistics
+
+import (
+    "context"
+    "flag"
+    "log"
+    "os"
+
+    "github.com/google/wire"
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+    "go.opentelemetry.io/otel/sdk/resource"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+    "go.opentelemetry.io/otel/trace"
+)
+
+var otlpEndpointFlag = flag.String(
+    "otlp-endpoint",
+    os.Getenv("OTLP_ENDPOINT"),
+    "OTLP gRPC collector endpoint (e.g. otel-collector:4317); tracing is a no-op when unset",
+)
+
+// ServiceSetForTracing providers
+var ServiceSetForTracing = wire.NewSet(NewTracerProvider)
+
+// NewTracerProvider builds an OTLP gRPC TracerProvider pointed at
+// --otlp-endpoint (or OTLP_ENDPOINT); when unset it falls back to a no-op
+// provider so tracing stays fully optional. The returned shutdown func must
+// be called on service shutdown to flush pending spans.
+func NewTracerProvider() (trace.TracerProvider, func(context.Context) error, error) {
+    endpoint := *otlpEndpointFlag
+    if endpoint == "" {
+        log.Println("otlp-endpoint not set, tracing disabled")
+        return trace.NewNoopTracerProvider(), func(context.Context) error { return nil }, nil
+    }
+
+    exporter, exporterErr := otlptracegrpc.New(
+        context.Background(),
+        otlptracegrpc.WithEndpoint(endpoint),
+        otlptracegrpc.WithInsecure(),
+    )
+    if exporterErr != nil {
+        return nil, nil, exporterErr
+    }
+
+    res, resourceErr := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(appName)))
+    if resourceErr != nil {
+        return nil, nil, resourceErr
+    }
+
+    tp := sdktrace.NewTracerProvider(
+        sdktrace.WithBatcher(exporter),
+        sdktrace.WithResource(res),
+    )
+    otel.SetTracerProvider(tp)
+
+    return tp, tp.Shutdown, nil
+}